@@ -0,0 +1,51 @@
+// Package auth provides pluggable client authentication for the proxy.
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Auth validates an incoming proxy request. When Validate returns false,
+// challenge holds the value the caller should send back in a
+// Proxy-Authenticate header.
+type Auth interface {
+	Validate(method, uri string, headers []string) (ok bool, challenge string)
+}
+
+// NewAuth builds an Auth implementation from a URL-style spec such as
+// "none://", "static://?username=alice&password=secret" or
+// "basicfile://?path=/etc/htpasswd&reload=60s". An empty spec disables
+// authentication.
+func NewAuth(paramstr string) (Auth, error) {
+	if strings.TrimSpace(paramstr) == "" {
+		return NewNoneAuth(), nil
+	}
+
+	u, err := url.Parse(paramstr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth spec %q: %w", paramstr, err)
+	}
+
+	switch u.Scheme {
+	case "", "none":
+		return NewNoneAuth(), nil
+	case "static":
+		return newStaticAuth(u.Query())
+	case "basicfile":
+		return newBasicFileAuth(u.Query())
+	default:
+		return nil, fmt.Errorf("unknown auth scheme %q", u.Scheme)
+	}
+}
+
+// Identity returns the username carried in a Proxy-Authorization: Basic
+// header, if any, for logging purposes. It does not validate the password.
+func Identity(headers []string) string {
+	user, _, ok := basicCredentials(headers)
+	if !ok {
+		return ""
+	}
+	return user
+}
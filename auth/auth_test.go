@@ -0,0 +1,75 @@
+package auth
+
+import "testing"
+
+func TestNewAuth(t *testing.T) {
+	cases := []struct {
+		name       string
+		spec       string
+		wantErr    bool
+		wantNoneOK bool
+	}{
+		{name: "empty spec defaults to none", spec: "", wantNoneOK: true},
+		{name: "none scheme", spec: "none://", wantNoneOK: true},
+		{name: "static scheme", spec: "static://?username=alice&password=secret"},
+		{name: "static missing password", spec: "static://?username=alice", wantErr: true},
+		{name: "unknown scheme", spec: "bogus://", wantErr: true},
+		{name: "invalid url", spec: "://", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := NewAuth(c.spec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewAuth(%q): %v", c.spec, err)
+			}
+			if c.wantNoneOK {
+				if ok, _ := a.Validate("GET", "/", nil); !ok {
+					t.Error("expected none auth to accept every request")
+				}
+			}
+		})
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	headers := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "secret")}
+	if got := Identity(headers); got != "alice" {
+		t.Errorf("Identity() = %q, want %q", got, "alice")
+	}
+	if got := Identity(nil); got != "" {
+		t.Errorf("Identity(nil) = %q, want empty", got)
+	}
+}
+
+func TestStaticAuthValidate(t *testing.T) {
+	a, err := newStaticAuth(map[string][]string{
+		"username": {"alice"},
+		"password": {"secret"},
+	})
+	if err != nil {
+		t.Fatalf("newStaticAuth: %v", err)
+	}
+
+	good := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "secret")}
+	if ok, _ := a.Validate("GET", "/", good); !ok {
+		t.Error("expected matching credentials to validate")
+	}
+
+	bad := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "wrong")}
+	if ok, challenge := a.Validate("GET", "/", bad); ok || challenge == "" {
+		t.Error("expected wrong password to fail with a challenge")
+	}
+}
+
+func TestNoneAuthValidate(t *testing.T) {
+	a := NewNoneAuth()
+	if ok, challenge := a.Validate("CONNECT", "example.com:443", nil); !ok || challenge != "" {
+		t.Errorf("Validate() = (%v, %q), want (true, \"\")", ok, challenge)
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// realm is sent in the Proxy-Authenticate challenge for both the static and
+// basicfile backends.
+const realm = "proxy"
+
+// basicCredentials extracts and base64-decodes the username/password carried
+// in a Proxy-Authorization: Basic header, if present.
+func basicCredentials(headers []string) (username, password string, ok bool) {
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx < 0 {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(h[:idx]), "Proxy-Authorization") {
+			continue
+		}
+
+		const prefix = "Basic "
+		value := strings.TrimSpace(h[idx+1:])
+		if !strings.HasPrefix(value, prefix) {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+		if err != nil {
+			continue
+		}
+
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+// basicChallenge builds the Proxy-Authenticate header value for realm r.
+func basicChallenge(r string) string {
+	return `Basic realm="` + r + `"`
+}
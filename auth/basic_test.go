@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// basicAuthHeader base64-encodes user:pass the way a client would for a
+// Proxy-Authorization: Basic header, for use across this package's tests.
+func basicAuthHeader(user, pass string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+}
+
+func TestBasicCredentials(t *testing.T) {
+	cases := []struct {
+		name     string
+		headers  []string
+		wantUser string
+		wantPass string
+		wantOK   bool
+	}{
+		{
+			name:     "valid header",
+			headers:  []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "secret")},
+			wantUser: "alice",
+			wantPass: "secret",
+			wantOK:   true,
+		},
+		{
+			name:     "case-insensitive header name",
+			headers:  []string{"proxy-authorization: Basic " + basicAuthHeader("bob", "pw")},
+			wantUser: "bob",
+			wantPass: "pw",
+			wantOK:   true,
+		},
+		{
+			name:    "missing header",
+			headers: []string{"Host: example.com"},
+			wantOK:  false,
+		},
+		{
+			name:    "not basic scheme",
+			headers: []string{"Proxy-Authorization: Bearer abcdef"},
+			wantOK:  false,
+		},
+		{
+			name:    "malformed base64",
+			headers: []string{"Proxy-Authorization: Basic not-base64!!"},
+			wantOK:  false,
+		},
+		{
+			name:    "no colon in decoded value",
+			headers: []string{"Proxy-Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("nocolon"))},
+			wantOK:  false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			user, pass, ok := basicCredentials(c.headers)
+			if ok != c.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, c.wantOK)
+			}
+			if ok && (user != c.wantUser || pass != c.wantPass) {
+				t.Errorf("got (%q, %q), want (%q, %q)", user, pass, c.wantUser, c.wantPass)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicFileAuth validates Basic credentials against an htpasswd-style file,
+// periodically reloading it when it changes on disk.
+type basicFileAuth struct {
+	path string
+
+	mu      sync.RWMutex
+	entries map[string]string // username -> password hash (or plaintext)
+	modTime time.Time
+}
+
+func newBasicFileAuth(q url.Values) (Auth, error) {
+	path := q.Get("path")
+	if path == "" {
+		return nil, fmt.Errorf("basicfile auth requires a path parameter")
+	}
+
+	reload := 60 * time.Second
+	if v := q.Get("reload"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reload duration %q: %w", v, err)
+		}
+		reload = d
+	}
+
+	a := &basicFileAuth{path: path}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	if reload > 0 {
+		go a.watch(reload)
+	}
+	return a, nil
+}
+
+// watch reloads the htpasswd file on a ticker, keeping the last good copy
+// in place if the file is unreadable (e.g. mid-write).
+func (a *basicFileAuth) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		a.load()
+	}
+}
+
+func (a *basicFileAuth) load() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fmt.Errorf("stat htpasswd file: %w", err)
+	}
+
+	a.mu.RLock()
+	unchanged := info.ModTime().Equal(a.modTime)
+	a.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	file, err := os.Open(a.path)
+	if err != nil {
+		return fmt.Errorf("open htpasswd file: %w", err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		entries[line[:idx]] = line[idx+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read htpasswd file: %w", err)
+	}
+
+	a.mu.Lock()
+	a.entries = entries
+	a.modTime = info.ModTime()
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *basicFileAuth) Validate(method, uri string, headers []string) (bool, string) {
+	user, pass, ok := basicCredentials(headers)
+	if !ok {
+		return false, basicChallenge(realm)
+	}
+
+	a.mu.RLock()
+	hash, found := a.entries[user]
+	a.mu.RUnlock()
+	if !found || !verifyPassword(hash, pass) {
+		return false, basicChallenge(realm)
+	}
+	return true, ""
+}
+
+// verifyPassword checks pass against an htpasswd hash, supporting bcrypt
+// ($2a$/$2b$/$2y$), {SHA} and plaintext entries. The legacy apr1 MD5 crypt
+// format is not supported.
+func verifyPassword(hash, pass string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(pass))
+		want := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(want)) == 1
+	default:
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(pass)) == 1
+	}
+}
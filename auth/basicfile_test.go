@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		hash string
+		pass string
+		want bool
+	}{
+		{"bcrypt match", string(bcryptHash), "secret", true},
+		{"bcrypt mismatch", string(bcryptHash), "wrong", false},
+		{"sha match", "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "secret", true},
+		{"sha mismatch", "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=", "wrong", false},
+		{"plaintext match", "secret", "secret", true},
+		{"plaintext mismatch", "secret", "wrong", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := verifyPassword(c.hash, c.pass); got != c.want {
+				t.Errorf("verifyPassword(%q, %q) = %v, want %v", c.hash, c.pass, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBasicFileAuthValidate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(path, []byte("alice:secret\n# comment\n\nbob:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	q := map[string][]string{"path": {path}}
+	a, err := newBasicFileAuth(q)
+	if err != nil {
+		t.Fatalf("newBasicFileAuth: %v", err)
+	}
+
+	headers := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "secret")}
+	if ok, _ := a.Validate("GET", "/", headers); !ok {
+		t.Error("expected alice:secret to validate")
+	}
+
+	badHeaders := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "wrong")}
+	if ok, challenge := a.Validate("GET", "/", badHeaders); ok || challenge == "" {
+		t.Error("expected alice:wrong to fail with a challenge")
+	}
+
+	if ok, _ := a.Validate("GET", "/", nil); ok {
+		t.Error("expected missing credentials to fail")
+	}
+}
+
+func TestBasicFileAuthReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(path, []byte("alice:secret\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	q := map[string][]string{"path": {path}}
+	authImpl, err := newBasicFileAuth(q)
+	if err != nil {
+		t.Fatalf("newBasicFileAuth: %v", err)
+	}
+	a := authImpl.(*basicFileAuth)
+
+	if err := os.WriteFile(path, []byte("alice:newpass\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := a.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	headers := []string{"Proxy-Authorization: Basic " + basicAuthHeader("alice", "newpass")}
+	if ok, _ := a.Validate("GET", "/", headers); !ok {
+		t.Error("expected reloaded password to validate")
+	}
+}
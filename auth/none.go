@@ -0,0 +1,14 @@
+package auth
+
+// noneAuth accepts every request unconditionally. It is the default when no
+// auth spec is configured.
+type noneAuth struct{}
+
+// NewNoneAuth returns an Auth that performs no authentication.
+func NewNoneAuth() Auth {
+	return noneAuth{}
+}
+
+func (noneAuth) Validate(method, uri string, headers []string) (bool, string) {
+	return true, ""
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+)
+
+// staticAuth validates every request against a single username/password
+// pair configured at startup.
+type staticAuth struct {
+	username string
+	password string
+}
+
+func newStaticAuth(q url.Values) (Auth, error) {
+	username := q.Get("username")
+	password := q.Get("password")
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("static auth requires username and password parameters")
+	}
+	return &staticAuth{username: username, password: password}, nil
+}
+
+func (a *staticAuth) Validate(method, uri string, headers []string) (bool, string) {
+	user, pass, ok := basicCredentials(headers)
+	if !ok {
+		return false, basicChallenge(realm)
+	}
+	userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(a.username)) == 1
+	passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(a.password)) == 1
+	if !userMatch || !passMatch {
+		return false, basicChallenge(realm)
+	}
+	return true, ""
+}
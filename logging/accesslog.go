@@ -0,0 +1,148 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Format selects how AccessLog renders each Entry.
+type Format string
+
+const (
+	FormatCombined Format = "combined"
+	FormatJSON     Format = "json"
+)
+
+// ParseFormat parses a format name; it defaults to FormatCombined for an
+// empty string.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatCombined:
+		return FormatCombined, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q", s)
+	}
+}
+
+// Entry describes one completed request. Status and Bytes are -1 when not
+// known (the plain HTTP and upstream-proxy paths stream bytes without
+// parsing the upstream response, so they can't always report them).
+// Duration, BytesIn and BytesOut are only populated for CONNECT tunnels.
+type Entry struct {
+	ClientIP  string
+	Identity  string
+	Timestamp time.Time
+	Method    string
+	URI       string
+	Proto     string
+	Status    int
+	Bytes     int64
+	Referer   string
+	UserAgent string
+
+	Duration time.Duration
+	BytesIn  int64
+	BytesOut int64
+}
+
+// AccessLog emits one line per completed request to a writer (typically a
+// *RotatingWriter) behind a mutex.
+type AccessLog struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format Format
+}
+
+// NewAccessLog returns an AccessLog writing to out in the given format.
+func NewAccessLog(out io.Writer, format Format) *AccessLog {
+	return &AccessLog{out: out, format: format}
+}
+
+// Log renders and writes e.
+func (a *AccessLog) Log(e Entry) {
+	var line string
+	if a.format == FormatJSON {
+		line = formatJSON(e)
+	} else {
+		line = formatCombined(e)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	io.WriteString(a.out, line+"\n")
+}
+
+// formatCombined renders e in Apache Combined Log Format:
+//
+//	client_ip - user [02/Jan/2006:15:04:05 -0700] "METHOD URI HTTP/x.y" status bytes "referer" "user-agent"
+func formatCombined(e Entry) string {
+	user := orDash(e.Identity)
+	referer := orDash(e.Referer)
+	agent := orDash(e.UserAgent)
+	status := "-"
+	if e.Status >= 0 {
+		status = fmt.Sprintf("%d", e.Status)
+	}
+	bytes := "-"
+	if e.Bytes >= 0 {
+		bytes = fmt.Sprintf("%d", e.Bytes)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] %q %s %s %q %q",
+		e.ClientIP, user, e.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
+		fmt.Sprintf("%s %s %s", e.Method, e.URI, e.Proto), status, bytes, referer, agent)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// jsonEntry is Entry's JSON shape; tunnel-only fields are omitted for plain
+// requests.
+type jsonEntry struct {
+	ClientIP   string `json:"client_ip"`
+	Identity   string `json:"identity,omitempty"`
+	Timestamp  string `json:"timestamp"`
+	Method     string `json:"method"`
+	URI        string `json:"uri"`
+	Proto      string `json:"proto"`
+	Status     int    `json:"status,omitempty"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	Referer    string `json:"referer,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	BytesIn    int64  `json:"bytes_in,omitempty"`
+	BytesOut   int64  `json:"bytes_out,omitempty"`
+}
+
+func formatJSON(e Entry) string {
+	je := jsonEntry{
+		ClientIP:   e.ClientIP,
+		Identity:   e.Identity,
+		Timestamp:  e.Timestamp.Format(time.RFC3339),
+		Method:     e.Method,
+		URI:        e.URI,
+		Proto:      e.Proto,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		Referer:    e.Referer,
+		UserAgent:  e.UserAgent,
+		DurationMS: e.Duration.Milliseconds(),
+		BytesIn:    e.BytesIn,
+		BytesOut:   e.BytesOut,
+	}
+
+	data, err := json.Marshal(je)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(data)
+}
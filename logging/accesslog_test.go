@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCombined(t *testing.T) {
+	e := Entry{
+		ClientIP:  "192.0.2.1",
+		Identity:  "alice",
+		Timestamp: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Method:    "GET",
+		URI:       "http://example.com/",
+		Proto:     "HTTP/1.1",
+		Status:    200,
+		Bytes:     1234,
+		Referer:   "",
+		UserAgent: "curl/8.0",
+	}
+
+	line := formatCombined(e)
+	want := `192.0.2.1 - alice [02/Jan/2026:15:04:05 +0000] "GET http://example.com/ HTTP/1.1" 200 1234 "-" "curl/8.0"`
+	if line != want {
+		t.Errorf("formatCombined() = %q, want %q", line, want)
+	}
+}
+
+func TestFormatCombinedUnknownStatusAndBytes(t *testing.T) {
+	e := Entry{ClientIP: "192.0.2.1", Method: "CONNECT", URI: "example.com:443", Proto: "HTTP/1.1", Status: -1, Bytes: -1}
+	line := formatCombined(e)
+	if !strings.Contains(line, ` - - `) {
+		t.Errorf("formatCombined() = %q, want dashes for unknown status/bytes", line)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	e := Entry{
+		ClientIP: "192.0.2.1",
+		Method:   "GET",
+		URI:      "/",
+		Proto:    "HTTP/1.1",
+		Status:   200,
+		Bytes:    42,
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(formatJSON(e)), &decoded); err != nil {
+		t.Fatalf("formatJSON produced invalid JSON: %v", err)
+	}
+	if decoded["client_ip"] != "192.0.2.1" {
+		t.Errorf("client_ip = %v, want 192.0.2.1", decoded["client_ip"])
+	}
+	if decoded["status"] != float64(200) {
+		t.Errorf("status = %v, want 200", decoded["status"])
+	}
+}
+
+func TestAccessLogWritesConfiguredFormat(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAccessLog(&buf, FormatJSON)
+	a.Log(Entry{ClientIP: "192.0.2.1", Method: "GET", URI: "/", Proto: "HTTP/1.1", Status: 200})
+
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("expected JSON output, got %q", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatCombined, false},
+		{"combined", FormatCombined, false},
+		{"json", FormatJSON, false},
+		{"xml", "", true},
+	}
+	for _, c := range cases {
+		got, err := ParseFormat(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+		}
+		if err == nil && got != c.want {
+			t.Errorf("ParseFormat(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package logging provides the proxy's diagnostic and access logging: a
+// leveled CondLogger for operational messages, and an AccessLog that emits
+// one structured line per completed request with file rotation.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogLevel filters which CondLogger calls are actually written.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+// ParseLogLevel parses a level name (case-insensitive); it defaults to
+// LevelInfo for an empty string.
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch s {
+	case "", "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	case "warning":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarning:
+		return "WARNING"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// CondLogger writes leveled diagnostic messages to an io.Writer behind a
+// mutex, dropping any call below the configured minimum level.
+type CondLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level LogLevel
+}
+
+// NewCondLogger returns a CondLogger writing to out, filtering out anything
+// below level.
+func NewCondLogger(out io.Writer, level LogLevel) *CondLogger {
+	return &CondLogger{out: out, level: level}
+}
+
+func (l *CondLogger) Debug(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+func (l *CondLogger) Info(format string, args ...interface{})  { l.logf(LevelInfo, format, args...) }
+func (l *CondLogger) Warning(format string, args ...interface{}) {
+	l.logf(LevelWarning, format, args...)
+}
+func (l *CondLogger) Error(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+func (l *CondLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	line := fmt.Sprintf("%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), level, fmt.Sprintf(format, args...))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line)
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCondLoggerFiltersBelowLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewCondLogger(&buf, LevelWarning)
+
+	l.Debug("debug message")
+	l.Info("info message")
+	l.Warning("warning message")
+	l.Error("error message")
+
+	out := buf.String()
+	if strings.Contains(out, "debug message") || strings.Contains(out, "info message") {
+		t.Errorf("expected Debug/Info to be filtered out, got %q", out)
+	}
+	if !strings.Contains(out, "warning message") || !strings.Contains(out, "error message") {
+		t.Errorf("expected Warning/Error to be logged, got %q", out)
+	}
+}
+
+func TestCondLoggerFormatsLevelAndMessage(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewCondLogger(&buf, LevelDebug)
+	l.Error("failed: %v", "boom")
+
+	if !strings.Contains(buf.String(), "[ERROR] failed: boom") {
+		t.Errorf("got %q, want it to contain [ERROR] failed: boom", buf.String())
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"", LevelInfo, false},
+		{"debug", LevelDebug, false},
+		{"warning", LevelWarning, false},
+		{"error", LevelError, false},
+		{"bogus", 0, true},
+	}
+	for _, c := range cases {
+		got, err := ParseLogLevel(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseLogLevel(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+		}
+		if err == nil && got != c.want {
+			t.Errorf("ParseLogLevel(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package logging
+
+import "io"
+
+// CountingReader wraps an io.Reader, tallying the bytes read through it.
+// It is not safe for concurrent use; callers reading from two goroutines
+// should use one CountingReader per goroutine.
+type CountingReader struct {
+	R     io.Reader
+	Count int64
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.Count += int64(n)
+	return n, err
+}
@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCountingReader(t *testing.T) {
+	r := &CountingReader{R: strings.NewReader("hello world")}
+
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if n != 5 || r.Count != 5 {
+		t.Errorf("n=%d Count=%d, want 5 and 5", n, r.Count)
+	}
+
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if r.Count != int64(5+len(rest)) {
+		t.Errorf("Count = %d, want %d", r.Count, 5+len(rest))
+	}
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RotatingWriter is an io.Writer backed by a file that rotates by size
+// and/or elapsed time. Rotation closes the current file, renames it aside
+// with a timestamp suffix, and reopens the original path.
+type RotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64         // 0 disables size-based rotation
+	interval time.Duration // 0 disables time-based rotation
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingWriter opens (creating if necessary) the file at path, rotating
+// it by maxSize bytes and/or interval, whichever comes first. Either limit
+// may be 0 to disable that trigger.
+func NewRotatingWriter(path string, maxSize int64, interval time.Duration) (*RotatingWriter, error) {
+	w := &RotatingWriter{path: path, maxSize: maxSize, interval: interval}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = time.Now()
+	return nil
+}
+
+// Write implements io.Writer, rotating first if p would push the file past
+// its size limit or its age past the configured interval.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) shouldRotate(nextWrite int) bool {
+	if w.maxSize > 0 && w.size+int64(nextWrite) > w.maxSize {
+		return true
+	}
+	if w.interval > 0 && time.Since(w.openedAt) >= w.interval {
+		return true
+	}
+	return false
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close log file for rotation: %w", err)
+	}
+
+	rotatedPath := fmt.Sprintf("%s.%s", w.path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(w.path, rotatedPath); err != nil {
+		return fmt.Errorf("rename log file for rotation: %w", err)
+	}
+
+	return w.open()
+}
+
+// Close closes the underlying file.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
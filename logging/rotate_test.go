@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	w, err := NewRotatingWriter(path, 10, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("12345")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := w.Write([]byte("678901")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d rotated files, want 1", len(matches))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "678901" {
+		t.Errorf("current log file = %q, want %q", data, "678901")
+	}
+}
+
+func TestRotatingWriterRotatesByInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	w, err := NewRotatingWriter(path, 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer w.Close()
+
+	time.Sleep(5 * time.Millisecond)
+	if _, err := w.Write([]byte("line\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d rotated files, want 1", len(matches))
+	}
+}
+
+func TestRotatingWriterNoRotationWhenDisabled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	w, err := NewRotatingWriter(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no rotation, got %d rotated files", len(matches))
+	}
+}
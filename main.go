@@ -1,22 +1,73 @@
 package main
 
 import (
-	"bufio"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"proxy-server/auth"
+	"proxy-server/logging"
+	"proxy-server/mitm"
+	"proxy-server/pool"
 	"proxy-server/proxy"
+	"proxy-server/rules"
 )
 
 // Config represents the proxy server configuration
 type Config struct {
-	Port             int    `json:"port"`
-	LogPath          string `json:"log_path"`
-	BlockedFilePath  string `json:"blocked_file_path"`
+	Port            int    `json:"port"`
+	LogPath         string `json:"log_path"`
+	BlockedFilePath string `json:"blocked_file_path"`
+	RulesFilePath   string `json:"rules_file_path"`
+	Auth            string `json:"auth"`
+
+	// MITM enables TLS man-in-the-middle inspection of CONNECT tunnels.
+	// Host selection is expressed as rules.MITM entries in the rules file.
+	MITMEnabled            bool   `json:"mitm_enabled"`
+	MITMCACertPath         string `json:"mitm_ca_cert_path"`
+	MITMCAKeyPath          string `json:"mitm_ca_key_path"`
+	MITMInsecureSkipVerify bool   `json:"mitm_insecure_skip_verify"`
+	// MITMRootCAPath is a PEM bundle of additional root CAs trusted when
+	// dialing MITM'd origins. Empty means the host's system root pool.
+	MITMRootCAPath string `json:"mitm_root_ca_path"`
+
+	// LogLevel filters CondLogger diagnostic messages written to LogPath.
+	LogLevel string `json:"log_level"`
+
+	// AccessLogPath is the destination for the per-request access log. If
+	// empty, access logging is disabled (AccessLog.Log becomes a no-op by
+	// writing to io.Discard).
+	AccessLogPath string `json:"access_log_path"`
+	// LogFormat selects "combined" (Apache Combined Log Format, the
+	// default) or "json" rendering for the access log.
+	LogFormat string `json:"log_format"`
+	// RotateSize, in bytes, rotates the access log once it grows past this
+	// size. 0 disables size-based rotation.
+	RotateSize int64 `json:"rotate_size"`
+	// RotateInterval rotates the access log once it has been open this
+	// long, e.g. "24h". Empty disables time-based rotation.
+	RotateInterval string `json:"rotate_interval"`
+
+	// PoolMaxIdlePerHost caps how many idle keep-alive connections are held
+	// per upstream host:port. 0 disables pooling.
+	PoolMaxIdlePerHost int `json:"pool_max_idle_per_host"`
+	// PoolIdleTimeout discards a pooled connection once it has sat idle this
+	// long, e.g. "90s". Empty disables idle eviction.
+	PoolIdleTimeout string `json:"pool_idle_timeout"`
+
+	// MetricsEnabled exposes pool hit/miss/eviction/in-use counters on
+	// MetricsAddr at /metrics. MetricsAddr defaults to "127.0.0.1:9090" if
+	// left empty.
+	MetricsEnabled bool   `json:"metrics_enabled"`
+	MetricsAddr    string `json:"metrics_addr"`
 }
 
 func main() {
@@ -26,19 +77,117 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Load blocked domains
-	blocklist, err := loadBlockedDomains(config.BlockedFilePath)
+	// Build the rule engine: the legacy blocklist file (if any) is expressed
+	// as HostSuffix -> Block rules and evaluated after the configured rules
+	// file, so explicit rules can override it.
+	engine := rules.NewEngine()
+	ruleSet, err := buildRuleSet(config)
+	if err != nil {
+		log.Fatalf("Failed to load rules: %v", err)
+	}
+	engine.SetRules(ruleSet)
+	log.Printf("Loaded %d rules", len(ruleSet))
+
+	// Build the authenticator
+	authenticator, err := auth.NewAuth(config.Auth)
 	if err != nil {
-		log.Fatalf("Failed to load blocked domains: %v", err)
+		log.Fatalf("Failed to configure auth: %v", err)
+	}
+
+	// Build MITM support, if enabled
+	opts := &proxy.Options{
+		Rules: engine,
+		Auth:  authenticator,
+	}
+	if config.MITMEnabled {
+		ca, err := mitm.LoadCA(config.MITMCACertPath, config.MITMCAKeyPath)
+		if err != nil {
+			log.Fatalf("Failed to load MITM CA: %v", err)
+		}
+		opts.MITMCA = ca
+		opts.MITMInsecureSkipVerify = config.MITMInsecureSkipVerify
+		if config.MITMRootCAPath != "" {
+			rootCAs, err := loadRootCAs(config.MITMRootCAPath)
+			if err != nil {
+				log.Fatalf("Failed to load MITM root CA bundle: %v", err)
+			}
+			opts.MITMRootCAs = rootCAs
+		}
 	}
-	log.Printf("Loaded %d blocked domains", len(blocklist))
 
-	// Open log file
+	// Reload the rules on SIGHUP without dropping in-flight connections;
+	// Engine.SetRules swaps the rule set atomically under its own lock.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			ruleSet, err := buildRuleSet(config)
+			if err != nil {
+				log.Printf("Failed to reload rules: %v", err)
+				continue
+			}
+			engine.SetRules(ruleSet)
+			log.Printf("Reloaded %d rules", len(ruleSet))
+		}
+	}()
+
+	// Build the diagnostic logger
 	logFile, err := os.OpenFile(config.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatalf("Failed to open log file: %v", err)
 	}
 	defer logFile.Close()
+	logLevel, err := logging.ParseLogLevel(config.LogLevel)
+	if err != nil {
+		log.Fatalf("Failed to configure log level: %v", err)
+	}
+	opts.Logger = logging.NewCondLogger(logFile, logLevel)
+
+	// Build the access logger
+	logFormat, err := logging.ParseFormat(config.LogFormat)
+	if err != nil {
+		log.Fatalf("Failed to configure log format: %v", err)
+	}
+	var rotateInterval time.Duration
+	if config.RotateInterval != "" {
+		rotateInterval, err = time.ParseDuration(config.RotateInterval)
+		if err != nil {
+			log.Fatalf("Failed to parse rotate_interval: %v", err)
+		}
+	}
+	accessLogOut := io.Writer(io.Discard)
+	if config.AccessLogPath != "" {
+		accessLogWriter, err := logging.NewRotatingWriter(config.AccessLogPath, config.RotateSize, rotateInterval)
+		if err != nil {
+			log.Fatalf("Failed to open access log: %v", err)
+		}
+		defer accessLogWriter.Close()
+		accessLogOut = accessLogWriter
+	}
+	opts.AccessLog = logging.NewAccessLog(accessLogOut, logFormat)
+
+	// Build the upstream connection pool
+	var poolIdleTimeout time.Duration
+	if config.PoolIdleTimeout != "" {
+		poolIdleTimeout, err = time.ParseDuration(config.PoolIdleTimeout)
+		if err != nil {
+			log.Fatalf("Failed to parse pool_idle_timeout: %v", err)
+		}
+	}
+	connPool := pool.New(config.PoolMaxIdlePerHost, poolIdleTimeout)
+	defer connPool.Close()
+	opts.Pool = connPool
+
+	if config.MetricsEnabled {
+		metricsAddr := config.MetricsAddr
+		if metricsAddr == "" {
+			// Default to loopback-only so an operator who enables metrics
+			// without setting an address doesn't end up exposing pool
+			// stats on every interface.
+			metricsAddr = "127.0.0.1:9090"
+		}
+		serveMetrics(metricsAddr, connPool)
+	}
 
 	// Start TCP listener
 	addr := fmt.Sprintf(":%d", config.Port)
@@ -59,10 +208,29 @@ func main() {
 		}
 
 		// Handle each connection in a goroutine
-		go proxy.HandleConnection(conn, blocklist, logFile)
+		go proxy.HandleConnection(conn, opts)
 	}
 }
 
+// serveMetrics starts a background HTTP server exposing the connection
+// pool's counters as plain-text at /metrics, Prometheus exposition style.
+func serveMetrics(addr string, connPool *pool.ConnPool) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		m := connPool.Metrics()
+		fmt.Fprintf(w, "proxy_pool_hits %d\n", m.Hits)
+		fmt.Fprintf(w, "proxy_pool_misses %d\n", m.Misses)
+		fmt.Fprintf(w, "proxy_pool_evictions %d\n", m.Evictions)
+		fmt.Fprintf(w, "proxy_pool_in_use %d\n", m.InUse)
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+}
+
 // loadConfig reads and parses the configuration file
 func loadConfig(path string) (*Config, error) {
 	file, err := os.Open(path)
@@ -80,26 +248,43 @@ func loadConfig(path string) (*Config, error) {
 	return &config, nil
 }
 
-// loadBlockedDomains reads the blocked domains file and returns a map for O(1) lookup
-func loadBlockedDomains(path string) (map[string]bool, error) {
-	file, err := os.Open(path)
+// loadRootCAs reads a PEM bundle of root certificates from path into a pool
+// used to verify MITM'd origin connections in place of the system roots.
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open blocked domains file: %w", err)
+		return nil, fmt.Errorf("read root CA bundle: %w", err)
 	}
-	defer file.Close()
 
-	blocklist := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		domain := strings.TrimSpace(scanner.Text())
-		if domain != "" && !strings.HasPrefix(domain, "#") {
-			blocklist[strings.ToLower(domain)] = true
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no valid certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// buildRuleSet loads the configured rules file (if any) followed by the
+// legacy blocklist file (if any) translated into HostSuffix -> Block rules,
+// so an explicit rule can still override a blocklist entry by matching
+// first.
+func buildRuleSet(config *Config) ([]rules.Rule, error) {
+	var ruleSet []rules.Rule
+
+	if config.RulesFilePath != "" {
+		fileRules, err := rules.LoadFile(config.RulesFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("load rules file: %w", err)
 		}
+		ruleSet = append(ruleSet, fileRules...)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading blocked domains: %w", err)
+	if config.BlockedFilePath != "" {
+		legacyRules, err := rules.BlockRulesFromHostFile(config.BlockedFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("load blocklist file: %w", err)
+		}
+		ruleSet = append(ruleSet, legacyRules...)
 	}
 
-	return blocklist, nil
+	return ruleSet, nil
 }
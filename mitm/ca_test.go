@@ -0,0 +1,86 @@
+package mitm
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCAGeneratesAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "ca.crt")
+	keyPath := filepath.Join(dir, "ca.key")
+
+	ca, err := LoadCA(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("LoadCA: %v", err)
+	}
+	if len(ca.CertPEM()) == 0 {
+		t.Fatal("expected a non-empty CA certificate PEM")
+	}
+
+	// Loading again should parse the persisted files rather than erroring.
+	ca2, err := LoadCA(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("LoadCA (reload): %v", err)
+	}
+	if string(ca2.CertPEM()) != string(ca.CertPEM()) {
+		t.Error("expected reloaded CA to match the persisted certificate")
+	}
+}
+
+func TestIssueLeafDNSName(t *testing.T) {
+	ca, key, err := generateCA()
+	if err != nil {
+		t.Fatalf("generateCA: %v", err)
+	}
+
+	leaf, err := issueLeaf(ca, key, "example.com")
+	if err != nil {
+		t.Fatalf("issueLeaf: %v", err)
+	}
+	if len(leaf.Leaf.DNSNames) != 1 || leaf.Leaf.DNSNames[0] != "example.com" {
+		t.Errorf("DNSNames = %v, want [example.com]", leaf.Leaf.DNSNames)
+	}
+	if len(leaf.Leaf.IPAddresses) != 0 {
+		t.Errorf("IPAddresses = %v, want none for a hostname", leaf.Leaf.IPAddresses)
+	}
+}
+
+func TestIssueLeafIPAddress(t *testing.T) {
+	ca, key, err := generateCA()
+	if err != nil {
+		t.Fatalf("generateCA: %v", err)
+	}
+
+	leaf, err := issueLeaf(ca, key, "203.0.113.10")
+	if err != nil {
+		t.Fatalf("issueLeaf: %v", err)
+	}
+	if len(leaf.Leaf.DNSNames) != 0 {
+		t.Errorf("DNSNames = %v, want none for an IP literal", leaf.Leaf.DNSNames)
+	}
+	if len(leaf.Leaf.IPAddresses) != 1 || !leaf.Leaf.IPAddresses[0].Equal(net.ParseIP("203.0.113.10")) {
+		t.Errorf("IPAddresses = %v, want [203.0.113.10]", leaf.Leaf.IPAddresses)
+	}
+}
+
+func TestIssueLeafLifetime(t *testing.T) {
+	ca, key, err := generateCA()
+	if err != nil {
+		t.Fatalf("generateCA: %v", err)
+	}
+
+	before := time.Now()
+	leaf, err := issueLeaf(ca, key, "example.com")
+	if err != nil {
+		t.Fatalf("issueLeaf: %v", err)
+	}
+	if !leaf.Leaf.NotAfter.After(before) {
+		t.Error("expected NotAfter to be in the future")
+	}
+	if leaf.Leaf.NotAfter.Sub(leaf.Leaf.NotBefore) < leafLifetime {
+		t.Error("expected the validity window to cover at least leafLifetime")
+	}
+}
@@ -0,0 +1,80 @@
+package mitm
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultCacheSize bounds the number of leaf certificates kept in memory.
+const defaultCacheSize = 1024
+
+// CA holds a loaded CA and generates/caches leaf certificates signed by it.
+type CA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	cache  *lru.Cache[string, *tls.Certificate]
+	flight singleflight.Group
+}
+
+// LoadCA loads the CA certificate/key pair from certPath/keyPath, generating
+// and persisting a new self-signed CA if either file does not yet exist.
+func LoadCA(certPath, keyPath string) (*CA, error) {
+	cert, key, err := loadOrCreateCA(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := lru.New[string, *tls.Certificate](defaultCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate cache: %w", err)
+	}
+
+	return &CA{cert: cert, key: key, cache: cache}, nil
+}
+
+// CertPEM returns the CA certificate in PEM form, for operators who need to
+// install it as a trusted root on MITM'd clients.
+func (c *CA) CertPEM() []byte {
+	return certToPEM(c.cert)
+}
+
+// GetCertificate returns a leaf certificate for serverName, signed by the
+// CA, generating and caching it on first use. Concurrent requests for the
+// same serverName coalesce onto a single generation.
+func (c *CA) GetCertificate(serverName string) (*tls.Certificate, error) {
+	if cert, ok := c.cache.Get(serverName); ok && !certExpired(cert) {
+		return cert, nil
+	}
+
+	result, err, _ := c.flight.Do(serverName, func() (interface{}, error) {
+		if cert, ok := c.cache.Get(serverName); ok && !certExpired(cert) {
+			return cert, nil
+		}
+
+		cert, err := issueLeaf(c.cert, c.key, serverName)
+		if err != nil {
+			return nil, err
+		}
+
+		c.cache.Add(serverName, cert)
+		return cert, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("issue certificate for %q: %w", serverName, err)
+	}
+
+	return result.(*tls.Certificate), nil
+}
+
+// certExpired reports whether a cached leaf certificate is past its
+// NotAfter and should be reissued rather than served stale.
+func certExpired(cert *tls.Certificate) bool {
+	return cert.Leaf == nil || time.Now().After(cert.Leaf.NotAfter)
+}
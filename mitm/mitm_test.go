@@ -0,0 +1,46 @@
+package mitm
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetCertificateCachesAndReissues(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadCA(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key"))
+	if err != nil {
+		t.Fatalf("LoadCA: %v", err)
+	}
+
+	first, err := ca.GetCertificate("example.com")
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	second, err := ca.GetCertificate("example.com")
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if first.Leaf.SerialNumber.Cmp(second.Leaf.SerialNumber) != 0 {
+		t.Error("expected a cached certificate to be reused on the second call")
+	}
+
+	// Force the cached entry to look expired and confirm it gets reissued
+	// rather than served stale.
+	expired := *first
+	expiredLeaf := *first.Leaf
+	expiredLeaf.NotAfter = time.Now().Add(-time.Minute)
+	expired.Leaf = &expiredLeaf
+	ca.cache.Add("example.com", &expired)
+
+	third, err := ca.GetCertificate("example.com")
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if third.Leaf.SerialNumber.Cmp(expired.Leaf.SerialNumber) == 0 {
+		t.Error("expected an expired cached certificate to be reissued")
+	}
+	if certExpired(third) {
+		t.Error("expected the reissued certificate to not be expired")
+	}
+}
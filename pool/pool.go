@@ -0,0 +1,176 @@
+// Package pool provides a keep-alive connection pool for upstream origin
+// connections, so handleHTTP doesn't have to dial a fresh TCP connection
+// for every request.
+package pool
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a point-in-time snapshot of a ConnPool's counters.
+type Metrics struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	InUse     int64
+}
+
+type idleConn struct {
+	conn      net.Conn
+	idleSince time.Time
+}
+
+// ConnPool holds idle upstream connections keyed by "host:port", evicting
+// anything that has sat idle past idleTimeout and capping how many
+// connections it will hold idle per key.
+type ConnPool struct {
+	maxIdlePerHost int
+	idleTimeout    time.Duration
+
+	mu   sync.Mutex
+	idle map[string][]idleConn
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	inUse     atomic.Int64
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// New returns a ConnPool holding at most maxIdlePerHost idle connections per
+// key, each discarded once it has been idle for idleTimeout. A maxIdlePerHost
+// of 0 disables pooling (every Put discards its connection) and an
+// idleTimeout of 0 disables the idle janitor.
+func New(maxIdlePerHost int, idleTimeout time.Duration) *ConnPool {
+	p := &ConnPool{
+		maxIdlePerHost: maxIdlePerHost,
+		idleTimeout:    idleTimeout,
+		idle:           make(map[string][]idleConn),
+		closeCh:        make(chan struct{}),
+	}
+	if idleTimeout > 0 {
+		go p.janitor()
+	}
+	return p
+}
+
+// Get returns an idle connection for key, if one is available and hasn't
+// expired. The caller owns the connection until it calls Put or Discard.
+func (p *ConnPool) Get(key string) (net.Conn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.idle[key]
+	now := time.Now()
+	for len(conns) > 0 {
+		c := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		if p.idleTimeout > 0 && now.Sub(c.idleSince) > p.idleTimeout {
+			c.conn.Close()
+			p.evictions.Add(1)
+			continue
+		}
+		p.idle[key] = conns
+		p.hits.Add(1)
+		p.inUse.Add(1)
+		return c.conn, true
+	}
+	p.idle[key] = conns
+	p.misses.Add(1)
+	return nil, false
+}
+
+// MarkAcquired records that the caller dialed a fresh connection itself
+// after a Get miss, so it still counts towards InUse until Put/Discard.
+func (p *ConnPool) MarkAcquired() {
+	p.inUse.Add(1)
+}
+
+// Put returns conn to the pool for reuse under key, unless the pool for that
+// key is already at capacity, in which case conn is closed and counted as an
+// eviction.
+func (p *ConnPool) Put(key string, conn net.Conn) {
+	p.inUse.Add(-1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idle[key]) >= p.maxIdlePerHost {
+		conn.Close()
+		p.evictions.Add(1)
+		return
+	}
+	p.idle[key] = append(p.idle[key], idleConn{conn: conn, idleSince: time.Now()})
+}
+
+// Discard closes conn without returning it to the pool, for use on any
+// read/write error or protocol violation.
+func (p *ConnPool) Discard(conn net.Conn) {
+	p.inUse.Add(-1)
+	conn.Close()
+}
+
+// Metrics returns a snapshot of the pool's counters.
+func (p *ConnPool) Metrics() Metrics {
+	return Metrics{
+		Hits:      p.hits.Load(),
+		Misses:    p.misses.Load(),
+		Evictions: p.evictions.Load(),
+		InUse:     p.inUse.Load(),
+	}
+}
+
+// Close stops the idle janitor and closes every pooled connection.
+func (p *ConnPool) Close() {
+	p.closeOnce.Do(func() { close(p.closeCh) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, conns := range p.idle {
+		for _, c := range conns {
+			c.conn.Close()
+		}
+		delete(p.idle, key)
+	}
+}
+
+func (p *ConnPool) janitor() {
+	ticker := time.NewTicker(p.idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closeCh:
+			return
+		case <-ticker.C:
+			p.evictExpired()
+		}
+	}
+}
+
+func (p *ConnPool) evictExpired() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, conns := range p.idle {
+		kept := conns[:0]
+		for _, c := range conns {
+			if now.Sub(c.idleSince) > p.idleTimeout {
+				c.conn.Close()
+				p.evictions.Add(1)
+				continue
+			}
+			kept = append(kept, c)
+		}
+		if len(kept) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = kept
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnPoolGetPutRoundTrip(t *testing.T) {
+	p := New(2, time.Minute)
+	defer p.Close()
+
+	if _, ok := p.Get("example.com:80"); ok {
+		t.Fatal("expected Get on an empty pool to miss")
+	}
+
+	client, server := net.Pipe()
+	defer server.Close()
+	p.Put("example.com:80", client)
+
+	got, ok := p.Get("example.com:80")
+	if !ok || got != client {
+		t.Fatalf("Get() = (%v, %v), want the connection just Put", got, ok)
+	}
+
+	m := p.Metrics()
+	if m.Hits != 1 || m.Misses != 1 {
+		t.Errorf("Metrics = %+v, want 1 hit and 1 miss", m)
+	}
+}
+
+func TestConnPoolCapsPerHost(t *testing.T) {
+	p := New(1, time.Minute)
+	defer p.Close()
+
+	c1, s1 := net.Pipe()
+	defer s1.Close()
+	c2, s2 := net.Pipe()
+	defer s2.Close()
+
+	p.Put("example.com:80", c1)
+	p.Put("example.com:80", c2)
+
+	m := p.Metrics()
+	if m.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1 (second Put should evict over the cap)", m.Evictions)
+	}
+
+	got, ok := p.Get("example.com:80")
+	if !ok || got != c1 {
+		t.Error("expected the first connection to remain pooled")
+	}
+}
+
+func TestConnPoolDiscardsExpiredOnGet(t *testing.T) {
+	p := New(2, time.Millisecond)
+	defer p.Close()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	p.Put("example.com:80", client)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := p.Get("example.com:80"); ok {
+		t.Error("expected an idle-expired connection not to be returned")
+	}
+	if p.Metrics().Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", p.Metrics().Evictions)
+	}
+}
+
+func TestConnPoolDiscard(t *testing.T) {
+	p := New(2, 0)
+	defer p.Close()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	p.MarkAcquired()
+	p.Discard(client)
+
+	if p.Metrics().InUse != 0 {
+		t.Errorf("InUse = %d, want 0 after Discard", p.Metrics().InUse)
+	}
+}
+
+func TestConnPoolZeroMaxIdleDisablesPooling(t *testing.T) {
+	p := New(0, 0)
+	defer p.Close()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	p.Put("example.com:80", client)
+
+	if _, ok := p.Get("example.com:80"); ok {
+		t.Error("expected pooling to be disabled when maxIdlePerHost is 0")
+	}
+}
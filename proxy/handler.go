@@ -2,32 +2,116 @@ package proxy
 
 import (
 	"bufio"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"proxy-server/auth"
+	"proxy-server/logging"
+	"proxy-server/mitm"
+	"proxy-server/pool"
+	"proxy-server/rules"
 )
 
+// Options bundles the shared dependencies every connection is handled with.
+type Options struct {
+	Rules     *rules.Engine
+	Auth      auth.Auth
+	Logger    *logging.CondLogger
+	AccessLog *logging.AccessLog
+	Pool      *pool.ConnPool
+
+	// MITM fields are nil/zero when MITM mode is disabled. A request only
+	// reaches MITM handling once a rule's Action is rules.MITM.
+	MITMCA                 *mitm.CA
+	MITMRootCAs            *x509.CertPool
+	MITMInsecureSkipVerify bool
+}
+
+// viaPseudonym identifies this proxy in the Via header added to every
+// forwarded request and response, per RFC 7230 5.7.1.
+const viaPseudonym = "Proxy Network Server"
+
+// hopByHopHeaders are stripped from every forwarded request and response,
+// per RFC 7230 6.1. Anything named in the request's own Connection header is
+// stripped in addition to this fixed set.
+var hopByHopHeaders = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-authenticate":  true,
+	"proxy-authorization": true,
+	"te":                  true,
+	"trailer":             true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+}
+
+// connectionTokens returns the lowercased header names listed in any
+// Connection header, which RFC 7230 6.1 requires treating as hop-by-hop in
+// addition to the fixed set above.
+func connectionTokens(headers []string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx < 0 || !strings.EqualFold(strings.TrimSpace(h[:idx]), "Connection") {
+			continue
+		}
+		for _, tok := range strings.Split(h[idx+1:], ",") {
+			tokens[strings.ToLower(strings.TrimSpace(tok))] = true
+		}
+	}
+	return tokens
+}
+
+// stripHopByHopHeaders removes hopByHopHeaders plus anything named in the
+// request's own Connection header.
+func stripHopByHopHeaders(headers []string) []string {
+	extra := connectionTokens(headers)
+	filtered := make([]string, 0, len(headers))
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx < 0 {
+			filtered = append(filtered, h)
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(h[:idx]))
+		if hopByHopHeaders[name] || extra[name] {
+			continue
+		}
+		filtered = append(filtered, h)
+	}
+	return filtered
+}
+
+// addViaHeader appends a Via header identifying this proxy.
+func addViaHeader(headers []string, protoVersion string) []string {
+	version := strings.TrimPrefix(protoVersion, "HTTP/")
+	return append(headers, fmt.Sprintf("Via: %s %s\r\n", version, viaPseudonym))
+}
+
 // HandleConnection processes an incoming proxy connection
-func HandleConnection(clientConn net.Conn, blocklist map[string]bool, logFile *os.File) {
+func HandleConnection(clientConn net.Conn, opts *Options) {
 	defer clientConn.Close()
 
 	// Read the first line of the request
 	reader := bufio.NewReader(clientConn)
 	requestLine, err := reader.ReadString('\n')
 	if err != nil {
-		logConnection(logFile, clientConn.RemoteAddr().String(), "", "", "ERROR", "Failed to read request")
+		opts.Logger.Debug("%s: failed to read request: %v", clientConn.RemoteAddr(), err)
 		return
 	}
 
 	// Parse the request line (METHOD URI HTTP/VERSION)
 	parts := strings.Fields(requestLine)
 	if len(parts) < 3 {
-		logConnection(logFile, clientConn.RemoteAddr().String(), "", "", "ERROR", "Invalid request line")
+		opts.Logger.Warning("%s: invalid request line %q", clientConn.RemoteAddr(), strings.TrimSpace(requestLine))
 		sendError(clientConn, 400, "Bad Request")
 		return
 	}
@@ -63,28 +147,138 @@ func HandleConnection(clientConn net.Conn, blocklist map[string]bool, logFile *o
 		targetHost, targetPort = extractHostFromURI(rawURI, hostHeader)
 	}
 
-	// Check blocklist
-	if isBlocked(targetHost, blocklist) {
-		logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, method, "BLOCKED", "Domain is blocked")
+	identity := auth.Identity(headers)
+	entry := baseEntry(clientConn, identity, method, rawURI, httpVersion, headers)
+
+	// Authenticate before doing anything else: before the 200 response for
+	// CONNECT, and before dialing the target for absolute-form requests.
+	if ok, challenge := opts.Auth.Validate(method, rawURI, headers); !ok {
+		entry.Status = 407
+		opts.AccessLog.Log(entry)
+		sendProxyAuthRequired(clientConn, challenge)
+		return
+	}
+
+	// Strip the credential header before forwarding anything upstream.
+	headers = stripProxyAuthorization(headers)
+
+	// Evaluate the rule pipeline; the first match wins. No match behaves as Allow.
+	rule, matched := opts.Rules.Evaluate(rules.Request{Host: targetHost, Port: targetPort, Method: method, URI: rawURI})
+	action := rules.Allow
+	if matched {
+		action = rule.Action
+	}
+
+	switch action {
+	case rules.Block:
+		entry.Status = 403
+		opts.AccessLog.Log(entry)
 		sendError(clientConn, 403, "Forbidden")
 		return
+
+	case rules.Redirect:
+		if method == "CONNECT" {
+			opts.Logger.Warning("%s: redirect rule is not applicable to CONNECT tunnels for %s", clientConn.RemoteAddr(), targetHost)
+			sendError(clientConn, 502, "Bad Gateway")
+			return
+		}
+		entry.Status = 302
+		opts.AccessLog.Log(entry)
+		sendRedirect(clientConn, rule.Target)
+		return
+
+	case rules.RewriteHost:
+		newHost, newPort := parseHostPort(rule.Target, targetPort)
+		if method != "CONNECT" {
+			rawURI, headers = rewriteRequestHost(rawURI, headers, newHost, newPort)
+		}
+		targetHost, targetPort = newHost, newPort
+
+	case rules.UpstreamProxy:
+		if method == "CONNECT" {
+			handleUpstreamConnect(clientConn, targetHost, targetPort, rule.Target, opts, identity)
+		} else {
+			handleUpstreamHTTP(clientConn, reader, method, rawURI, httpVersion, headers, targetHost, targetPort, rule.Target, opts, identity)
+		}
+		return
+
+	case rules.MITM:
+		// MITM only makes sense for CONNECT tunnels; a matching rule on a
+		// plain HTTP request behaves like Allow.
+		if method == "CONNECT" {
+			if opts.MITMCA == nil {
+				opts.Logger.Error("%s: MITM rule matched %s but no MITM CA is configured", clientConn.RemoteAddr(), targetHost)
+				sendError(clientConn, 502, "Bad Gateway")
+				return
+			}
+			handleMITM(clientConn, targetHost, targetPort, opts, identity)
+			return
+		}
 	}
 
 	if method == "CONNECT" {
-		handleHTTPS(clientConn, targetHost, targetPort, logFile)
+		handleHTTPS(clientConn, targetHost, targetPort, opts, identity)
 	} else {
-		handleHTTP(clientConn, reader, method, rawURI, httpVersion, headers, targetHost, targetPort, logFile)
+		handleHTTP(clientConn, reader, method, rawURI, httpVersion, headers, targetHost, targetPort, opts, identity)
+	}
+}
+
+// stripProxyAuthorization removes the Proxy-Authorization header so client
+// credentials are never forwarded to the origin server.
+func stripProxyAuthorization(headers []string) []string {
+	filtered := make([]string, 0, len(headers))
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx >= 0 && strings.EqualFold(strings.TrimSpace(h[:idx]), "Proxy-Authorization") {
+			continue
+		}
+		filtered = append(filtered, h)
+	}
+	return filtered
+}
+
+// rewriteRequestHost rewrites the Host header (and the authority of an
+// absolute-form URI) to newHost/newPort, for the RewriteHost rule action.
+func rewriteRequestHost(rawURI string, headers []string, newHost, newPort string) (string, []string) {
+	authority := newHost
+	if newPort != "" && newPort != "80" && newPort != "443" {
+		authority = net.JoinHostPort(newHost, newPort)
+	}
+
+	if strings.HasPrefix(rawURI, "http://") || strings.HasPrefix(rawURI, "https://") {
+		if u, err := url.Parse(rawURI); err == nil {
+			u.Host = authority
+			rawURI = u.String()
+		}
+	}
+
+	rewritten := make([]string, 0, len(headers)+1)
+	replaced := false
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx >= 0 && strings.EqualFold(strings.TrimSpace(h[:idx]), "Host") {
+			rewritten = append(rewritten, fmt.Sprintf("Host: %s\r\n", authority))
+			replaced = true
+			continue
+		}
+		rewritten = append(rewritten, h)
 	}
+	if !replaced {
+		rewritten = append(rewritten, fmt.Sprintf("Host: %s\r\n", authority))
+	}
+
+	return rawURI, rewritten
 }
 
 // handleHTTPS handles CONNECT requests for HTTPS tunneling
-func handleHTTPS(clientConn net.Conn, targetHost, targetPort string, logFile *os.File) {
+func handleHTTPS(clientConn net.Conn, targetHost, targetPort string, opts *Options, identity string) {
 	targetAddr := net.JoinHostPort(targetHost, targetPort)
+	start := time.Now()
 
 	// Connect to target server
 	targetConn, err := net.DialTimeout("tcp", targetAddr, 10*time.Second)
 	if err != nil {
-		logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, "CONNECT", "ERROR", fmt.Sprintf("Failed to connect: %v", err))
+		opts.Logger.Error("CONNECT %s: failed to connect: %v", targetHost, err)
 		sendError(clientConn, 502, "Bad Gateway")
 		return
 	}
@@ -93,99 +287,274 @@ func handleHTTPS(clientConn net.Conn, targetHost, targetPort string, logFile *os
 	// Send connection established response
 	_, err = clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
 	if err != nil {
-		logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, "CONNECT", "ERROR", "Failed to send response")
+		opts.Logger.Error("CONNECT %s: failed to send response: %v", targetHost, err)
 		return
 	}
 
-	logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, "CONNECT", "OK", "Tunnel established")
-
-	// Bidirectional copy with WaitGroup for proper synchronization
-	var wg sync.WaitGroup
-	wg.Add(2)
+	clientToTarget := &logging.CountingReader{R: clientConn}
+	targetToClient := &logging.CountingReader{R: targetConn}
+	relayBidirectional(clientConn, clientToTarget, targetConn, targetToClient)
 
-	// Client -> Target
-	go func() {
-		defer wg.Done()
-		io.Copy(targetConn, clientConn)
-		// Signal EOF to target
-		if tcpConn, ok := targetConn.(*net.TCPConn); ok {
-			tcpConn.CloseWrite()
-		}
-	}()
+	opts.AccessLog.Log(tunnelEntry(clientConn, identity, targetAddr, 200, start, clientToTarget.Count, targetToClient.Count))
+}
 
-	// Target -> Client
-	go func() {
-		defer wg.Done()
-		io.Copy(clientConn, targetConn)
-		// Signal EOF to client
-		if tcpConn, ok := clientConn.(*net.TCPConn); ok {
-			tcpConn.CloseWrite()
+// handleHTTP handles a single regular HTTP request, using a pooled
+// keep-alive connection to the target when one is available.
+func handleHTTP(clientConn net.Conn, reader *bufio.Reader, method, rawURI, httpVersion string, headers []string, targetHost, targetPort string, opts *Options, identity string) {
+	entry := baseEntry(clientConn, identity, method, rawURI, httpVersion, headers)
+	targetKey := net.JoinHostPort(targetHost, targetPort)
+
+	targetConn, pooled := opts.Pool.Get(targetKey)
+	if pooled && !connAlive(targetConn) {
+		// The origin may have closed this idle connection on its own
+		// schedule, independent of our pool's idle timeout.
+		opts.Pool.Discard(targetConn)
+		targetConn, pooled = nil, false
+	}
+	if !pooled {
+		var err error
+		targetConn, err = net.DialTimeout("tcp", targetKey, 10*time.Second)
+		if err != nil {
+			opts.Logger.Error("%s %s: failed to connect: %v", method, targetHost, err)
+			entry.Status = 502
+			opts.AccessLog.Log(entry)
+			sendError(clientConn, 502, "Bad Gateway")
+			return
 		}
-	}()
+		opts.Pool.MarkAcquired()
+	}
 
-	// Wait for both directions to complete
-	wg.Wait()
-}
+	clientWantsKeepAlive := !connectionTokens(headers)["close"] && httpVersion != "HTTP/1.0"
 
-// handleHTTP handles regular HTTP requests
-func handleHTTP(clientConn net.Conn, reader *bufio.Reader, method, rawURI, httpVersion string, headers []string, targetHost, targetPort string, logFile *os.File) {
-	targetAddr := net.JoinHostPort(targetHost, targetPort)
+	chunkedBody := strings.EqualFold(headerValue(headers, "Transfer-Encoding"), "chunked")
+	forwardHeaders := stripHopByHopHeaders(headers)
+	if chunkedBody {
+		// copyRequestBody relays the chunked body verbatim, so the
+		// Transfer-Encoding header announcing it must survive the
+		// hop-by-hop strip.
+		forwardHeaders = append(forwardHeaders, "Transfer-Encoding: chunked\r\n")
+	}
+	forwardHeaders = addViaHeader(forwardHeaders, httpVersion)
+	requestLine := fmt.Sprintf("%s %s %s\r\n", method, cleanRequestURI(rawURI), httpVersion)
+
+	if err := writeRequest(targetConn, requestLine, forwardHeaders, reader, headers); err != nil {
+		opts.Logger.Error("%s %s: failed to forward request: %v", method, targetHost, err)
+		opts.Pool.Discard(targetConn)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
 
-	// Connect to target server
-	targetConn, err := net.DialTimeout("tcp", targetAddr, 10*time.Second)
+	resp, err := http.ReadResponse(bufio.NewReader(targetConn), &http.Request{Method: method})
 	if err != nil {
-		logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, method, "ERROR", fmt.Sprintf("Failed to connect: %v", err))
+		opts.Logger.Error("%s %s: failed to read response: %v", method, targetHost, err)
+		opts.Pool.Discard(targetConn)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
 		sendError(clientConn, 502, "Bad Gateway")
 		return
 	}
-	defer targetConn.Close()
 
-	// Clean the request URI (convert absolute to relative)
-	cleanedURI := cleanRequestURI(rawURI)
+	resp.Header.Del("Connection")
+	resp.Header.Del("Keep-Alive")
+	resp.Header.Del("Proxy-Authenticate")
+	resp.Header.Del("Trailer")
+	resp.Header.Del("Upgrade")
+	resp.Header.Set("Via", fmt.Sprintf("%s %s", strings.TrimPrefix(httpVersion, "HTTP/"), viaPseudonym))
 
-	// Forward the request to target
-	requestLine := fmt.Sprintf("%s %s %s\r\n", method, cleanedURI, httpVersion)
-	_, err = targetConn.Write([]byte(requestLine))
-	if err != nil {
-		logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, method, "ERROR", "Failed to forward request")
-		sendError(clientConn, 502, "Bad Gateway")
+	writeErr := resp.Write(clientConn)
+	resp.Body.Close()
+
+	entry.Status = resp.StatusCode
+	entry.Bytes = resp.ContentLength
+	opts.AccessLog.Log(entry)
+
+	if writeErr != nil {
+		opts.Logger.Error("%s %s: failed to relay response: %v", method, targetHost, writeErr)
+		opts.Pool.Discard(targetConn)
 		return
 	}
 
-	// Forward headers
-	for _, header := range headers {
-		targetConn.Write([]byte(header))
+	if clientWantsKeepAlive && !resp.Close {
+		opts.Pool.Put(targetKey, targetConn)
+	} else {
+		opts.Pool.Discard(targetConn)
+	}
+}
+
+// connAlive reports whether a pooled connection is still usable, by probing
+// for a read that would indicate the peer already closed it: origins are
+// free to close idle connections on their own schedule, independent of the
+// pool's configured idle timeout.
+func connAlive(conn net.Conn) bool {
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer conn.SetReadDeadline(time.Time{})
+
+	one := make([]byte, 1)
+	n, err := conn.Read(one)
+	if n > 0 {
+		// Unsolicited data on an idle connection can't be put back; treat
+		// the connection as unusable.
+		return false
+	}
+	if err == nil {
+		return true
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+	return false
+}
+
+// writeRequest writes the request line, forwardHeaders and a blank line to
+// dst, then copies the request body (if any) from reader, framed per the
+// original (pre-stripping) headers' Content-Length or Transfer-Encoding.
+func writeRequest(dst io.Writer, requestLine string, forwardHeaders []string, reader *bufio.Reader, originalHeaders []string) error {
+	if _, err := io.WriteString(dst, requestLine); err != nil {
+		return err
+	}
+	for _, h := range forwardHeaders {
+		if _, err := io.WriteString(dst, h); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(dst, "\r\n"); err != nil {
+		return err
+	}
+	return copyRequestBody(dst, reader, originalHeaders)
+}
+
+// copyRequestBody forwards a request body of known framing: chunked
+// transfer-encoding is relayed chunk-by-chunk (including trailers), a
+// Content-Length body is copied for exactly that many bytes, and anything
+// else is assumed to have no body.
+func copyRequestBody(dst io.Writer, reader *bufio.Reader, headers []string) error {
+	if strings.EqualFold(headerValue(headers, "Transfer-Encoding"), "chunked") {
+		return copyChunkedBody(dst, reader)
+	}
+	if cl := headerValue(headers, "Content-Length"); cl != "" {
+		length, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Content-Length: %w", err)
+		}
+		_, err = io.CopyN(dst, reader, length)
+		return err
 	}
-	targetConn.Write([]byte("\r\n"))
+	return nil
+}
+
+// copyChunkedBody relays a chunked request body verbatim, chunk size lines,
+// chunk data and trailers included, stopping after the terminating 0-size
+// chunk and its trailer section.
+func copyChunkedBody(dst io.Writer, reader *bufio.Reader) error {
+	for {
+		sizeLine, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(dst, sizeLine); err != nil {
+			return err
+		}
+
+		sizeField := strings.TrimSpace(strings.SplitN(sizeLine, ";", 2)[0])
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chunk size: %w", err)
+		}
 
-	logConnection(logFile, clientConn.RemoteAddr().String(), targetHost, method, "OK", cleanedURI)
+		if size == 0 {
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return err
+				}
+				if _, err := io.WriteString(dst, line); err != nil {
+					return err
+				}
+				if line == "\r\n" || line == "\n" {
+					return nil
+				}
+			}
+		}
+
+		if _, err := io.CopyN(dst, reader, size+2); err != nil { // +2 for trailing CRLF
+			return err
+		}
+	}
+}
 
-	// Bidirectional copy with WaitGroup for proper synchronization
+// relayBidirectional copies clientReader -> upstream and upstreamReader ->
+// client concurrently, half-closing each TCP side once its direction hits
+// EOF, and waits for both directions to finish.
+func relayBidirectional(client net.Conn, clientReader io.Reader, upstream net.Conn, upstreamReader io.Reader) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	// Client -> Target (forward any remaining body data)
 	go func() {
 		defer wg.Done()
-		io.Copy(targetConn, reader)
-		if tcpConn, ok := targetConn.(*net.TCPConn); ok {
+		io.Copy(upstream, clientReader)
+		if tcpConn, ok := upstream.(*net.TCPConn); ok {
 			tcpConn.CloseWrite()
 		}
 	}()
 
-	// Target -> Client
 	go func() {
 		defer wg.Done()
-		io.Copy(clientConn, targetConn)
-		if tcpConn, ok := clientConn.(*net.TCPConn); ok {
+		io.Copy(client, upstreamReader)
+		if tcpConn, ok := client.(*net.TCPConn); ok {
 			tcpConn.CloseWrite()
 		}
 	}()
 
-	// Wait for both directions to complete
 	wg.Wait()
 }
 
+// baseEntry builds the common fields of an access log entry for a parsed
+// request; Status/Bytes default to -1 (unknown) until the caller fills them in.
+func baseEntry(clientConn net.Conn, identity, method, uri, proto string, headers []string) logging.Entry {
+	return logging.Entry{
+		ClientIP:  clientConn.RemoteAddr().String(),
+		Identity:  identity,
+		Timestamp: time.Now(),
+		Method:    method,
+		URI:       uri,
+		Proto:     proto,
+		Status:    -1,
+		Bytes:     -1,
+		Referer:   headerValue(headers, "Referer"),
+		UserAgent: headerValue(headers, "User-Agent"),
+	}
+}
+
+// tunnelEntry builds an access log entry for a completed CONNECT tunnel.
+func tunnelEntry(clientConn net.Conn, identity, targetAddr string, status int, start time.Time, bytesIn, bytesOut int64) logging.Entry {
+	return logging.Entry{
+		ClientIP:  clientConn.RemoteAddr().String(),
+		Identity:  identity,
+		Timestamp: time.Now(),
+		Method:    "CONNECT",
+		URI:       targetAddr,
+		Proto:     "HTTP/1.1",
+		Status:    status,
+		Bytes:     bytesIn + bytesOut,
+		Duration:  time.Since(start),
+		BytesIn:   bytesIn,
+		BytesOut:  bytesOut,
+	}
+}
+
+// headerValue returns the value of the first header named name, or "" if absent.
+func headerValue(headers []string, name string) string {
+	for _, h := range headers {
+		idx := strings.Index(h, ":")
+		if idx >= 0 && strings.EqualFold(strings.TrimSpace(h[:idx]), name) {
+			return strings.TrimSpace(h[idx+1:])
+		}
+	}
+	return ""
+}
+
 // parseHostPort splits host:port string, using defaultPort if not specified
 func parseHostPort(addr, defaultPort string) (string, string) {
 	host, port, err := net.SplitHostPort(addr)
@@ -239,35 +608,6 @@ func cleanRequestURI(rawURI string) string {
 	return rawURI
 }
 
-// isBlocked checks if the host is in the blocklist
-func isBlocked(host string, blocklist map[string]bool) bool {
-	// Normalize host (lowercase, strip port if present)
-	host = strings.ToLower(host)
-	// Use SplitHostPort only when host includes a port; if it errors, keep host unchanged
-	if h, _, err := net.SplitHostPort(host); err == nil {
-		host = h
-	}
-	if host == "" {
-		return false
-	}
-
-	// Check exact match
-	if blocklist[host] {
-		return true
-	}
-
-	// Check parent domains (e.g., sub.example.com should match example.com)
-	parts := strings.Split(host, ".")
-	for i := 1; i < len(parts); i++ {
-		parentDomain := strings.Join(parts[i:], ".")
-		if blocklist[parentDomain] {
-			return true
-		}
-	}
-
-	return false
-}
-
 // sendError sends an HTTP error response to the client
 func sendError(conn net.Conn, statusCode int, statusText string) {
 	response := fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\n%d %s\n",
@@ -275,12 +615,17 @@ func sendError(conn net.Conn, statusCode int, statusText string) {
 	conn.Write([]byte(response))
 }
 
-// logConnection logs connection details to the log file
-func logConnection(logFile *os.File, clientAddr, targetHost, method, status, details string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	logLine := fmt.Sprintf("[%s] Client: %s | Host: %s | Method: %s | Status: %s | Details: %s\n",
-		timestamp, clientAddr, targetHost, method, status, details)
+// sendProxyAuthRequired sends a 407 response carrying the Proxy-Authenticate
+// challenge returned by the configured Auth implementation.
+func sendProxyAuthRequired(conn net.Conn, challenge string) {
+	response := fmt.Sprintf("HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: %s\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\n407 Proxy Authentication Required\n",
+		challenge)
+	conn.Write([]byte(response))
+}
 
-	// Thread-safe write using file lock
-	logFile.WriteString(logLine)
+// sendRedirect sends an HTTP redirect response to location, for the
+// Redirect rule action.
+func sendRedirect(conn net.Conn, location string) {
+	response := fmt.Sprintf("HTTP/1.1 302 Found\r\nLocation: %s\r\nContent-Length: 0\r\nConnection: close\r\n\r\n", location)
+	conn.Write([]byte(response))
 }
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestStripHopByHopHeaders(t *testing.T) {
+	headers := []string{
+		"Host: example.com\r\n",
+		"Connection: close, X-Custom\r\n",
+		"X-Custom: drop-me\r\n",
+		"Proxy-Authorization: Basic abc\r\n",
+		"Accept: */*\r\n",
+	}
+
+	got := stripHopByHopHeaders(headers)
+	want := []string{"Host: example.com\r\n", "Accept: */*\r\n"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("header %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConnectionTokens(t *testing.T) {
+	headers := []string{"Connection: close, X-Foo\r\n"}
+	tokens := connectionTokens(headers)
+	if !tokens["close"] || !tokens["x-foo"] {
+		t.Errorf("tokens = %v, want close and x-foo", tokens)
+	}
+	if len(connectionTokens(nil)) != 0 {
+		t.Error("expected no tokens when there's no Connection header")
+	}
+}
+
+func TestAddViaHeader(t *testing.T) {
+	got := addViaHeader(nil, "HTTP/1.1")
+	if len(got) != 1 || got[0] != "Via: 1.1 "+viaPseudonym+"\r\n" {
+		t.Errorf("addViaHeader() = %v", got)
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	headers := []string{"Content-Length: 42\r\n", "Host: example.com\r\n"}
+	if v := headerValue(headers, "content-length"); v != "42" {
+		t.Errorf("headerValue() = %q, want 42", v)
+	}
+	if v := headerValue(headers, "X-Missing"); v != "" {
+		t.Errorf("headerValue() = %q, want empty", v)
+	}
+}
+
+func TestCleanRequestURI(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"http://example.com/path?q=1", "/path?q=1"},
+		{"http://example.com", "/"},
+		{"/already/relative", "/already/relative"},
+	}
+	for _, c := range cases {
+		if got := cleanRequestURI(c.in); got != c.want {
+			t.Errorf("cleanRequestURI(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestCopyChunkedBody(t *testing.T) {
+	raw := "5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"
+	reader := bufio.NewReader(strings.NewReader(raw))
+
+	var buf strings.Builder
+	if err := copyChunkedBody(&buf, reader); err != nil {
+		t.Fatalf("copyChunkedBody: %v", err)
+	}
+	if buf.String() != raw {
+		t.Errorf("copyChunkedBody relayed %q, want %q", buf.String(), raw)
+	}
+}
+
+func TestCopyChunkedBodyWithTrailer(t *testing.T) {
+	raw := "3\r\nabc\r\n0\r\nX-Trailer: value\r\n\r\n"
+	reader := bufio.NewReader(strings.NewReader(raw))
+
+	var buf strings.Builder
+	if err := copyChunkedBody(&buf, reader); err != nil {
+		t.Fatalf("copyChunkedBody: %v", err)
+	}
+	if buf.String() != raw {
+		t.Errorf("copyChunkedBody relayed %q, want %q", buf.String(), raw)
+	}
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"proxy-server/logging"
+)
+
+// handleMITM terminates the CONNECT tunnel's TLS locally using a certificate
+// generated for the SNI name, re-establishes TLS to the real origin, and
+// relays individual HTTP requests/responses between the two, logging each
+// one. It is only used when the target host is selected for MITM.
+func handleMITM(clientConn net.Conn, targetHost, targetPort string, opts *Options, identity string) {
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		opts.Logger.Error("MITM %s: failed to send response: %v", targetHost, err)
+		return
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			serverName := hello.ServerName
+			if serverName == "" {
+				serverName = targetHost
+			}
+			return opts.MITMCA.GetCertificate(serverName)
+		},
+	}
+
+	clientTLSConn := tls.Server(clientConn, tlsConfig)
+	defer clientTLSConn.Close()
+
+	if err := clientTLSConn.Handshake(); err != nil {
+		opts.Logger.Error("MITM %s: handshake failed: %v", targetHost, err)
+		return
+	}
+
+	serverName := clientTLSConn.ConnectionState().ServerName
+	if serverName == "" {
+		serverName = targetHost
+	}
+
+	targetAddr := net.JoinHostPort(targetHost, targetPort)
+	originConn, err := tls.Dial("tcp", targetAddr, &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            opts.MITMRootCAs,
+		InsecureSkipVerify: opts.MITMInsecureSkipVerify,
+	})
+	if err != nil {
+		opts.Logger.Error("MITM %s: failed to dial origin: %v", targetHost, err)
+		return
+	}
+	defer originConn.Close()
+
+	opts.Logger.Info("MITM %s: tunnel established", targetHost)
+
+	clientReader := bufio.NewReader(clientTLSConn)
+	originReader := bufio.NewReader(originConn)
+
+	for {
+		start := time.Now()
+		req, err := http.ReadRequest(clientReader)
+		if err != nil {
+			if err != io.EOF {
+				opts.Logger.Error("MITM %s: failed to read request: %v", targetHost, err)
+			}
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = targetAddr
+
+		entry := logging.Entry{
+			ClientIP:  clientConn.RemoteAddr().String(),
+			Identity:  identity,
+			Timestamp: time.Now(),
+			Method:    req.Method,
+			URI:       req.URL.String(),
+			Proto:     req.Proto,
+			Status:    -1,
+			Bytes:     -1,
+			Referer:   req.Referer(),
+			UserAgent: req.UserAgent(),
+		}
+
+		if err := req.Write(originConn); err != nil {
+			opts.Logger.Error("MITM %s: failed to forward request: %v", targetHost, err)
+			opts.AccessLog.Log(entry)
+			return
+		}
+
+		resp, err := http.ReadResponse(originReader, req)
+		if err != nil {
+			opts.Logger.Error("MITM %s: failed to read origin response: %v", targetHost, err)
+			opts.AccessLog.Log(entry)
+			return
+		}
+
+		writeErr := resp.Write(clientTLSConn)
+		resp.Body.Close()
+		entry.Status = resp.StatusCode
+		entry.Bytes = resp.ContentLength
+		entry.Duration = time.Since(start)
+		opts.AccessLog.Log(entry)
+		if writeErr != nil {
+			opts.Logger.Error("MITM %s: failed to relay response: %v", targetHost, writeErr)
+			return
+		}
+
+		if req.Close || resp.Close {
+			return
+		}
+	}
+}
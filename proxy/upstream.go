@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"proxy-server/logging"
+)
+
+// handleUpstreamConnect services a CONNECT request by tunneling through a
+// parent proxy instead of dialing the origin directly, nesting a CONNECT of
+// our own and propagating Proxy-Authorization if the parent URL carries
+// credentials.
+func handleUpstreamConnect(clientConn net.Conn, targetHost, targetPort, parentURL string, opts *Options, identity string) {
+	entry := baseEntry(clientConn, identity, "CONNECT", net.JoinHostPort(targetHost, targetPort), "HTTP/1.1", nil)
+	start := time.Now()
+
+	parent, err := url.Parse(parentURL)
+	if err != nil {
+		opts.Logger.Error("CONNECT %s: invalid upstream proxy URL: %v", targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+
+	parentConn, err := net.DialTimeout("tcp", parent.Host, 10*time.Second)
+	if err != nil {
+		opts.Logger.Error("CONNECT %s: failed to dial upstream proxy: %v", targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+	defer parentConn.Close()
+
+	targetAddr := net.JoinHostPort(targetHost, targetPort)
+	request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", targetAddr, targetAddr)
+	request += proxyAuthorizationHeader(parent)
+	request += "\r\n"
+
+	if _, err := parentConn.Write([]byte(request)); err != nil {
+		opts.Logger.Error("CONNECT %s: failed to forward to upstream proxy: %v", targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+
+	parentReader := bufio.NewReader(parentConn)
+	statusLine, err := parentReader.ReadString('\n')
+	if err != nil || !strings.Contains(statusLine, " 200 ") {
+		opts.Logger.Error("CONNECT %s: upstream proxy refused CONNECT: %s", targetHost, strings.TrimSpace(statusLine))
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+	for {
+		line, err := parentReader.ReadString('\n')
+		if err != nil || line == "\r\n" || line == "\n" {
+			break
+		}
+	}
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		opts.Logger.Error("CONNECT %s: failed to send response: %v", targetHost, err)
+		return
+	}
+
+	opts.Logger.Info("CONNECT %s: tunneled via upstream proxy %s", targetHost, parent.Host)
+
+	clientToParent := &logging.CountingReader{R: clientConn}
+	parentToClient := &logging.CountingReader{R: parentReader}
+	relayBidirectional(clientConn, clientToParent, parentConn, parentToClient)
+
+	entry.Status = 200
+	entry.Bytes = clientToParent.Count + parentToClient.Count
+	entry.Duration = time.Since(start)
+	entry.BytesIn = clientToParent.Count
+	entry.BytesOut = parentToClient.Count
+	opts.AccessLog.Log(entry)
+}
+
+// handleUpstreamHTTP forwards a regular HTTP request to a parent proxy
+// instead of dialing the origin directly, reconstructing an absolute-form
+// request line if the client sent a relative one.
+func handleUpstreamHTTP(clientConn net.Conn, reader *bufio.Reader, method, rawURI, httpVersion string, headers []string, targetHost, targetPort, parentURL string, opts *Options, identity string) {
+	entry := baseEntry(clientConn, identity, method, rawURI, httpVersion, headers)
+
+	parent, err := url.Parse(parentURL)
+	if err != nil {
+		opts.Logger.Error("%s %s: invalid upstream proxy URL: %v", method, targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+
+	parentConn, err := net.DialTimeout("tcp", parent.Host, 10*time.Second)
+	if err != nil {
+		opts.Logger.Error("%s %s: failed to dial upstream proxy: %v", method, targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+	defer parentConn.Close()
+
+	absoluteURI := rawURI
+	if !strings.HasPrefix(rawURI, "http://") && !strings.HasPrefix(rawURI, "https://") {
+		absoluteURI = fmt.Sprintf("http://%s%s", net.JoinHostPort(targetHost, targetPort), rawURI)
+	}
+
+	requestLine := fmt.Sprintf("%s %s %s\r\n", method, absoluteURI, httpVersion)
+	if _, err := parentConn.Write([]byte(requestLine)); err != nil {
+		opts.Logger.Error("%s %s: failed to forward request to upstream proxy: %v", method, targetHost, err)
+		entry.Status = 502
+		opts.AccessLog.Log(entry)
+		sendError(clientConn, 502, "Bad Gateway")
+		return
+	}
+
+	forwardHeaders := stripHopByHopHeaders(headers)
+	forwardHeaders = addViaHeader(forwardHeaders, httpVersion)
+	for _, header := range forwardHeaders {
+		parentConn.Write([]byte(header))
+	}
+	parentConn.Write([]byte(proxyAuthorizationHeader(parent)))
+	parentConn.Write([]byte("\r\n"))
+
+	opts.Logger.Info("%s %s: forwarded via upstream proxy %s", method, targetHost, parent.Host)
+	opts.AccessLog.Log(entry)
+
+	relayBidirectional(clientConn, reader, parentConn, parentConn)
+}
+
+// proxyAuthorizationHeader builds a Proxy-Authorization header line from a
+// parent proxy URL's userinfo, or an empty string if it carries none.
+func proxyAuthorizationHeader(parent *url.URL) string {
+	if parent.User == nil {
+		return ""
+	}
+	password, _ := parent.User.Password()
+	creds := base64.StdEncoding.EncodeToString([]byte(parent.User.Username() + ":" + password))
+	return fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", creds)
+}
@@ -0,0 +1,41 @@
+package rules
+
+import "sync"
+
+// Engine holds the current rule set and evaluates requests against it. Rules
+// may be swapped at any time via SetRules without disrupting connections
+// already in flight, since Evaluate takes a read lock and readers never
+// block on each other.
+type Engine struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// NewEngine returns an Engine with no rules loaded; Evaluate reports no
+// match for every request until SetRules is called.
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// SetRules atomically replaces the engine's rule set, e.g. after a SIGHUP
+// reload.
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+}
+
+// Evaluate returns the first Rule matching req, in load order. ok is false
+// if no rule matched, meaning the caller should fall back to its default
+// behavior (allow).
+func (e *Engine) Evaluate(req Request) (rule Rule, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, r := range e.rules {
+		if r.Matcher.Match(req) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleSpec is the on-disk representation of a Rule, as loaded from YAML or
+// JSON.
+type ruleSpec struct {
+	Match  matchSpec `json:"match" yaml:"match"`
+	Action string    `json:"action" yaml:"action"`
+	Target string    `json:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// matchSpec is the on-disk representation of a Matcher. Type selects which
+// matcher Value is interpreted by:
+//
+//	host        - exact hostname
+//	host_suffix - hostname or any subdomain of it (the legacy blocklist shape)
+//	host_regex  - regexp against the hostname
+//	method      - comma-separated list of HTTP methods
+//	port        - comma-separated list of ports
+//	url_regex   - regexp against the request URI
+type matchSpec struct {
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// LoadFile reads a rules file in YAML or JSON (selected by extension) and
+// compiles it into an ordered list of Rules.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+
+	var specs []ruleSpec
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &specs); err != nil {
+			return nil, fmt.Errorf("parse rules YAML: %w", err)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &specs); err != nil {
+			return nil, fmt.Errorf("parse rules JSON: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rules file extension %q", ext)
+	}
+
+	rules := make([]Rule, 0, len(specs))
+	for i, spec := range specs {
+		rule, err := compileRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func compileRule(spec ruleSpec) (Rule, error) {
+	action, err := parseAction(spec.Action)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	matcher, err := compileMatcher(spec.Match)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	if (action == RewriteHost || action == UpstreamProxy || action == Redirect) && spec.Target == "" {
+		return Rule{}, fmt.Errorf("action %q requires a target", action)
+	}
+
+	return Rule{Matcher: matcher, Action: action, Target: spec.Target}, nil
+}
+
+func compileMatcher(spec matchSpec) (Matcher, error) {
+	switch spec.Type {
+	case "host":
+		return hostExactMatcher{host: spec.Value}, nil
+	case "host_suffix":
+		return hostSuffixMatcher{suffix: spec.Value}, nil
+	case "host_regex":
+		re, err := regexp.Compile(spec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("compile host_regex: %w", err)
+		}
+		return hostRegexMatcher{re: re}, nil
+	case "method":
+		methods := make(map[string]bool)
+		for _, m := range strings.Split(spec.Value, ",") {
+			methods[strings.ToUpper(strings.TrimSpace(m))] = true
+		}
+		return methodSetMatcher{methods: methods}, nil
+	case "port":
+		ports := make(map[string]bool)
+		for _, p := range strings.Split(spec.Value, ",") {
+			ports[strings.TrimSpace(p)] = true
+		}
+		return portSetMatcher{ports: ports}, nil
+	case "url_regex":
+		re, err := regexp.Compile(spec.Value)
+		if err != nil {
+			return nil, fmt.Errorf("compile url_regex: %w", err)
+		}
+		return urlPathRegexMatcher{re: re}, nil
+	default:
+		return nil, fmt.Errorf("unknown matcher type %q", spec.Type)
+	}
+}
+
+// BlockRulesFromHostFile reads a newline-delimited hostname file (the legacy
+// blocklist format: blank lines and lines starting with # ignored) and
+// returns the equivalent HostSuffix -> Block rules, preserving today's
+// "sub.example.com blocked by example.com" behavior.
+func BlockRulesFromHostFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read blocklist file: %w", err)
+	}
+
+	var out []Rule
+	for _, line := range strings.Split(string(data), "\n") {
+		host := strings.TrimSpace(line)
+		if host == "" || strings.HasPrefix(host, "#") {
+			continue
+		}
+		out = append(out, Rule{Matcher: hostSuffixMatcher{suffix: strings.ToLower(host)}, Action: Block})
+	}
+	return out, nil
+}
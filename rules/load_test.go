@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFileYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	yamlDoc := `
+- match:
+    type: host_suffix
+    value: ads.example.com
+  action: block
+- match:
+    type: host
+    value: api.internal
+  action: rewrite_host
+  target: internal.svc.local
+`
+	if err := os.WriteFile(path, []byte(yamlDoc), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("got %d rules, want 2", len(loaded))
+	}
+	if loaded[0].Action != Block {
+		t.Errorf("rule 0 action = %v, want %v", loaded[0].Action, Block)
+	}
+	if loaded[1].Action != RewriteHost || loaded[1].Target != "internal.svc.local" {
+		t.Errorf("rule 1 = %+v, want RewriteHost to internal.svc.local", loaded[1])
+	}
+}
+
+func TestCompileRuleRequiresTarget(t *testing.T) {
+	_, err := compileRule(ruleSpec{
+		Match:  matchSpec{Type: "host", Value: "api.internal"},
+		Action: "rewrite_host",
+	})
+	if err == nil {
+		t.Fatal("expected an error when rewrite_host has no target")
+	}
+}
+
+func TestCompileMatcherUnknownType(t *testing.T) {
+	if _, err := compileMatcher(matchSpec{Type: "bogus"}); err == nil {
+		t.Fatal("expected an error for an unknown matcher type")
+	}
+}
+
+func TestBlockRulesFromHostFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist")
+	content := "ads.example.com\n# a comment\n\nbad.example.org\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := BlockRulesFromHostFile(path)
+	if err != nil {
+		t.Fatalf("BlockRulesFromHostFile: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("got %d rules, want 2", len(loaded))
+	}
+	for _, r := range loaded {
+		if r.Action != Block {
+			t.Errorf("action = %v, want %v", r.Action, Block)
+		}
+	}
+	if !loaded[0].Matcher.Match(Request{Host: "sub.ads.example.com"}) {
+		t.Error("expected the blocklist suffix rule to match a subdomain")
+	}
+}
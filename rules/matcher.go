@@ -0,0 +1,64 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hostExactMatcher matches a single hostname exactly (case-insensitive).
+type hostExactMatcher struct {
+	host string
+}
+
+func (m hostExactMatcher) Match(req Request) bool {
+	return strings.EqualFold(req.Host, m.host)
+}
+
+// hostSuffixMatcher matches a host equal to, or a subdomain of, suffix. This
+// is the matcher the legacy blocklist is expressed with.
+type hostSuffixMatcher struct {
+	suffix string
+}
+
+func (m hostSuffixMatcher) Match(req Request) bool {
+	host := strings.ToLower(req.Host)
+	suffix := strings.ToLower(m.suffix)
+	return host == suffix || strings.HasSuffix(host, "."+suffix)
+}
+
+// hostRegexMatcher matches a host against a compiled regular expression.
+type hostRegexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m hostRegexMatcher) Match(req Request) bool {
+	return m.re.MatchString(req.Host)
+}
+
+// methodSetMatcher matches requests whose method is one of a fixed set.
+type methodSetMatcher struct {
+	methods map[string]bool
+}
+
+func (m methodSetMatcher) Match(req Request) bool {
+	return m.methods[strings.ToUpper(req.Method)]
+}
+
+// portSetMatcher matches requests whose target port is one of a fixed set.
+type portSetMatcher struct {
+	ports map[string]bool
+}
+
+func (m portSetMatcher) Match(req Request) bool {
+	return m.ports[req.Port]
+}
+
+// urlPathRegexMatcher matches the request URI against a compiled regular
+// expression.
+type urlPathRegexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m urlPathRegexMatcher) Match(req Request) bool {
+	return m.re.MatchString(req.URI)
+}
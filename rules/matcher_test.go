@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestHostExactMatcher(t *testing.T) {
+	m := hostExactMatcher{host: "Example.com"}
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.COM", true},
+		{"sub.example.com", false},
+		{"notexample.com", false},
+	}
+	for _, c := range cases {
+		if got := m.Match(Request{Host: c.host}); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.host, got, c.want)
+		}
+	}
+}
+
+func TestHostSuffixMatcher(t *testing.T) {
+	m := hostSuffixMatcher{suffix: "Example.com"}
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"sub.example.com", true},
+		{"a.b.example.com", true},
+		{"notexample.com", false},
+		{"example.com.evil", false},
+	}
+	for _, c := range cases {
+		if got := m.Match(Request{Host: c.host}); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.host, got, c.want)
+		}
+	}
+}
+
+func TestHostRegexMatcher(t *testing.T) {
+	m := hostRegexMatcher{re: regexp.MustCompile(`^.*\.ads\..*$`)}
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{"foo.ads.example.com", true},
+		{"ads.example.com", false},
+		{"example.com", false},
+	}
+	for _, c := range cases {
+		if got := m.Match(Request{Host: c.host}); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.host, got, c.want)
+		}
+	}
+}
+
+func TestMethodSetMatcher(t *testing.T) {
+	m := methodSetMatcher{methods: map[string]bool{"GET": true, "HEAD": true}}
+
+	cases := []struct {
+		method string
+		want   bool
+	}{
+		{"GET", true},
+		{"get", true},
+		{"POST", false},
+	}
+	for _, c := range cases {
+		if got := m.Match(Request{Method: c.method}); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.method, got, c.want)
+		}
+	}
+}
+
+func TestPortSetMatcher(t *testing.T) {
+	m := portSetMatcher{ports: map[string]bool{"443": true}}
+
+	if !m.Match(Request{Port: "443"}) {
+		t.Error("expected port 443 to match")
+	}
+	if m.Match(Request{Port: "80"}) {
+		t.Error("expected port 80 not to match")
+	}
+}
+
+func TestURLPathRegexMatcher(t *testing.T) {
+	m := urlPathRegexMatcher{re: regexp.MustCompile(`^/admin`)}
+
+	if !m.Match(Request{URI: "/admin/users"}) {
+		t.Error("expected /admin/users to match")
+	}
+	if m.Match(Request{URI: "/public"}) {
+		t.Error("expected /public not to match")
+	}
+}
+
+func TestEngineEvaluateFirstMatchWins(t *testing.T) {
+	e := NewEngine()
+	e.SetRules([]Rule{
+		{Matcher: hostSuffixMatcher{suffix: "example.com"}, Action: Block},
+		{Matcher: hostSuffixMatcher{suffix: "sub.example.com"}, Action: Allow},
+	})
+
+	rule, ok := e.Evaluate(Request{Host: "sub.example.com"})
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if rule.Action != Block {
+		t.Errorf("Action = %v, want %v (first rule should win)", rule.Action, Block)
+	}
+
+	if _, ok := e.Evaluate(Request{Host: "other.com"}); ok {
+		t.Error("expected no match for unrelated host")
+	}
+}
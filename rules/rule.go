@@ -0,0 +1,60 @@
+// Package rules implements the request handler pipeline: an ordered list of
+// Rules, each pairing a Matcher against an Action, evaluated first-match-wins
+// in place of the old boolean blocklist check.
+package rules
+
+import "fmt"
+
+// Action describes what HandleConnection should do with a request that
+// matched a Rule.
+type Action string
+
+const (
+	// Allow lets the request proceed as normal.
+	Allow Action = "allow"
+	// Block rejects the request (403 for HTTP, tunnel refused for CONNECT).
+	Block Action = "block"
+	// Redirect responds with an HTTP redirect to Target instead of forwarding.
+	Redirect Action = "redirect"
+	// RewriteHost rewrites the Host header/authority to Target before forwarding.
+	RewriteHost Action = "rewrite_host"
+	// UpstreamProxy forwards the request through the proxy named by Target
+	// instead of dialing the origin directly.
+	UpstreamProxy Action = "upstream_proxy"
+	// MITM terminates the CONNECT tunnel locally for inspection instead of
+	// tunneling it blindly. It only applies to CONNECT requests.
+	MITM Action = "mitm"
+)
+
+// Rule pairs a Matcher with the Action to take when it matches.
+type Rule struct {
+	Matcher Matcher
+	Action  Action
+
+	// Target carries the action's parameter: the new host for RewriteHost,
+	// the destination URL for Redirect, or the parent proxy URL for
+	// UpstreamProxy. Unused by Allow, Block and MITM.
+	Target string
+}
+
+// Request is the subset of an inbound request a Matcher needs to see.
+type Request struct {
+	Host   string
+	Port   string
+	Method string
+	URI    string
+}
+
+// Matcher decides whether a Request satisfies a rule.
+type Matcher interface {
+	Match(req Request) bool
+}
+
+func parseAction(s string) (Action, error) {
+	switch Action(s) {
+	case Allow, Block, Redirect, RewriteHost, UpstreamProxy, MITM:
+		return Action(s), nil
+	default:
+		return "", fmt.Errorf("unknown rule action %q", s)
+	}
+}